@@ -0,0 +1,138 @@
+package proxypool
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+	"time"
+)
+
+// ProxyEvent describes a proxy alive/dead state transition observed by the
+// health checker.
+type ProxyEvent struct {
+	Proxy *Proxy
+	Alive bool
+	Time  time.Time
+}
+
+// StartHealthChecker launches a background goroutine that continuously
+// revalidates the pool's proxies: dead proxies are retested on an
+// exponential backoff schedule capped at maxBackoff instead of the fixed
+// RetestDelay, and alive proxies are re-probed every interval to catch
+// silent failures. testURLs are checked round-robin; if empty, pool.TestURL
+// is used. A nil validator falls back to the default "status 200" check.
+// The checker runs until ctx is canceled or Stop is called.
+func (pool *ProxyPool) StartHealthChecker(ctx context.Context, interval, maxBackoff time.Duration, testURLs []string, validator func([]byte, *http.Response) bool) {
+	if len(testURLs) == 0 {
+		testURLs = []string{pool.TestURL}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	pool.hcCancel = cancel
+	pool.hcEvents = make(chan ProxyEvent, 16)
+	pool.hcBackoffs = make(map[*Proxy]time.Duration)
+
+	pool.hcWG.Add(1)
+	go pool.runHealthChecker(ctx, interval, maxBackoff, testURLs, validator)
+}
+
+// Stop halts any background goroutines started by StartHealthChecker or
+// AddSource and waits for them to exit.
+func (pool *ProxyPool) Stop() {
+	if pool.hcCancel != nil {
+		pool.hcCancel()
+	}
+	pool.hcWG.Wait()
+
+	if pool.srcCancel != nil {
+		pool.srcCancel()
+	}
+	pool.srcWG.Wait()
+}
+
+// Events returns the channel on which proxy state transitions are
+// published. It is only valid after StartHealthChecker has been called.
+func (pool *ProxyPool) Events() <-chan ProxyEvent {
+	return pool.hcEvents
+}
+
+func (pool *ProxyPool) runHealthChecker(ctx context.Context, interval, maxBackoff time.Duration, testURLs []string, validator func([]byte, *http.Response) bool) {
+	defer pool.hcWG.Done()
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			pool.probeAll(interval, maxBackoff, testURLs, validator)
+		}
+	}
+}
+
+func (pool *ProxyPool) probeAll(interval, maxBackoff time.Duration, testURLs []string, validator func([]byte, *http.Response) bool) {
+	pool.proxiesMu.RLock()
+	proxies := make([]*Proxy, len(pool.proxies))
+	copy(proxies, pool.proxies)
+	pool.proxiesMu.RUnlock()
+
+	for _, proxy := range proxies {
+		if proxy.IsAlive() || pool.dueForRetest(proxy, interval) {
+			go pool.probe(proxy, interval, maxBackoff, testURLs, validator)
+		}
+	}
+}
+
+func (pool *ProxyPool) dueForRetest(proxy *Proxy, interval time.Duration) bool {
+	pool.hcMu.Lock()
+	backoff, ok := pool.hcBackoffs[proxy]
+	pool.hcMu.Unlock()
+	if !ok {
+		backoff = interval
+	}
+	return time.Since(proxy.LastRequest()) > backoff
+}
+
+func (pool *ProxyPool) nextTestURL(testURLs []string) string {
+	i := atomic.AddUint64(&pool.hcURLIndex, 1)
+	return testURLs[int(i-1)%len(testURLs)]
+}
+
+func (pool *ProxyPool) probe(proxy *Proxy, interval, maxBackoff time.Duration, testURLs []string, validator func([]byte, *http.Response) bool) {
+	wasAlive := proxy.IsAlive()
+	testURL := pool.nextTestURL(testURLs)
+
+	var alive bool
+	if validator != nil {
+		alive = proxy.checkWithValidator(testURL, validator)
+	} else {
+		_, err := proxy.Get(testURL)
+		alive = err == nil
+	}
+
+	pool.hcMu.Lock()
+	if alive {
+		delete(pool.hcBackoffs, proxy)
+	} else {
+		next := pool.hcBackoffs[proxy] * 2
+		if next == 0 {
+			next = interval
+		}
+		if next > maxBackoff {
+			next = maxBackoff
+		}
+		pool.hcBackoffs[proxy] = next
+	}
+	pool.hcMu.Unlock()
+
+	pool.UpdateCounts()
+
+	if alive != wasAlive {
+		select {
+		case pool.hcEvents <- ProxyEvent{Proxy: proxy, Alive: alive, Time: time.Now()}:
+		default:
+		}
+	}
+}
@@ -0,0 +1,16 @@
+package proxypool
+
+// Logger receives diagnostic messages from the pool and its proxies. It is
+// satisfied by *log.Logger, so callers can plug in their own destination
+// instead of proxies printing to stdout or LoadProxies crashing the process.
+type Logger interface {
+	Printf(format string, args ...interface{})
+}
+
+// logf logs through the proxy's configured Logger, if any. A nil Logger
+// silently drops the message.
+func (proxy *Proxy) logf(format string, args ...interface{}) {
+	if proxy.logger != nil {
+		proxy.logger.Printf(format, args...)
+	}
+}
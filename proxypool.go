@@ -1,33 +1,108 @@
 package proxypool
 
 import (
-	"bufio"
+	"context"
 	"errors"
 	"fmt"
 	"io/ioutil"
-	"log"
 	"net"
 	"net/http"
-	"os"
-	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"h12.io/socks"
 )
 
-// Proxy represents a single SOCKS 4/5 proxy
+// Proxy represents a single proxy, which may speak SOCKS4/5 or plain
+// HTTP/HTTPS. protocol is one of "socks4", "socks5", "http", "https", or
+// empty to auto-detect SOCKS4/5 on Create.
 type Proxy struct {
-	URL         string
-	Alive       bool
-	protocol    string
-	ip          string
-	port        int
-	lastRequest time.Time
-	dial        func(string, string) (net.Conn, error)
-	transport   *http.Transport
-	client      *http.Client
+	URL          string
+	protocol     string
+	ip           string
+	port         int
+	user         string
+	pass         string
+	alive        int32 // atomic: 0 = dead, 1 = alive
+	lastRequest  int64 // atomic: UnixNano of the last request attempt
+	dial         func(string, string) (net.Conn, error)
+	transport    *http.Transport
+	client       *http.Client
+	successCount int64
+	failureCount int64
+	latencyEWMA  int64 // nanoseconds, updated via atomic ops
+	logger       Logger
+}
+
+// IsAlive reports whether the proxy passed its last health check. Safe to
+// call concurrently with Get/checkWithValidator/Do probing the same proxy.
+func (proxy *Proxy) IsAlive() bool {
+	return atomic.LoadInt32(&proxy.alive) == 1
+}
+
+// setAlive atomically updates the proxy's liveness flag.
+func (proxy *Proxy) setAlive(alive bool) {
+	var v int32
+	if alive {
+		v = 1
+	}
+	atomic.StoreInt32(&proxy.alive, v)
+}
+
+// LastRequest returns the time of the proxy's most recent request attempt.
+func (proxy *Proxy) LastRequest() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&proxy.lastRequest))
+}
+
+// touch atomically records that a request attempt is starting now.
+func (proxy *Proxy) touch() {
+	atomic.StoreInt64(&proxy.lastRequest, time.Now().UnixNano())
+}
+
+// setLastRequest atomically sets the proxy's last-request timestamp to t,
+// for restoring a saved state rather than marking a live attempt.
+func (proxy *Proxy) setLastRequest(t time.Time) {
+	atomic.StoreInt64(&proxy.lastRequest, t.UnixNano())
+}
+
+// latencyEWMAWeight is the smoothing factor applied to each new latency
+// sample; lower values weigh historical samples more heavily.
+const latencyEWMAWeight = 0.2
+
+// SuccessCount returns how many requests this proxy has completed successfully.
+func (proxy *Proxy) SuccessCount() int64 {
+	return atomic.LoadInt64(&proxy.successCount)
+}
+
+// FailureCount returns how many requests this proxy has failed.
+func (proxy *Proxy) FailureCount() int64 {
+	return atomic.LoadInt64(&proxy.failureCount)
+}
+
+// Latency returns the proxy's exponentially weighted moving average response time.
+func (proxy *Proxy) Latency() time.Duration {
+	return time.Duration(atomic.LoadInt64(&proxy.latencyEWMA))
+}
+
+// recordResult updates the proxy's rolling stats after a request attempt.
+func (proxy *Proxy) recordResult(success bool, elapsed time.Duration) {
+	if success {
+		atomic.AddInt64(&proxy.successCount, 1)
+	} else {
+		atomic.AddInt64(&proxy.failureCount, 1)
+	}
+
+	for {
+		old := atomic.LoadInt64(&proxy.latencyEWMA)
+		var next int64
+		if old == 0 {
+			next = int64(elapsed)
+		} else {
+			next = old + int64(latencyEWMAWeight*float64(int64(elapsed)-old))
+		}
+		if atomic.CompareAndSwapInt64(&proxy.latencyEWMA, old, next) {
+			break
+		}
+	}
 }
 
 // ProxyPool manages a group of proxies.
@@ -35,138 +110,193 @@ type Proxy struct {
 // RetestDelay is after how many seconds should a proxy be retested if its is unavailable (manually call Pool.Test()).
 type ProxyPool struct {
 	proxies        []*Proxy
+	proxiesMu      sync.RWMutex
+	selector       Selector
+	selectorMu     sync.Mutex
+	Logger         Logger
 	TestURL        string
 	RateLimit      int
 	RetestDelay    int
 	AliveCount     int
 	AvailableCount int
+
+	hcCancel   context.CancelFunc
+	hcWG       sync.WaitGroup
+	hcEvents   chan ProxyEvent
+	hcBackoffs map[*Proxy]time.Duration
+	hcMu       sync.Mutex
+	hcURLIndex uint64
+
+	srcOnce   sync.Once
+	srcCtx    context.Context
+	srcCancel context.CancelFunc
+	srcWG     sync.WaitGroup
+
+	countsMu sync.Mutex
+}
+
+// SetSelector configures the strategy used by GetAvailableProxy to pick
+// among the proxies currently eligible for a request.
+func (pool *ProxyPool) SetSelector(selector Selector) {
+	pool.selector = selector
 }
 
-// Create checks if its v4 or v5, constructs the dial, transport, client and finally tests it.
+// Create builds the dial, transport and client for the proxy according to
+// its scheme and finally tests it. Proxies with an explicit "http"/"https"
+// protocol get an http.Transport routed through http.ProxyURL; an explicit
+// "socks4"/"socks5" protocol uses that SOCKS version; everything else is
+// tried as SOCKS5 then SOCKS4 via socks.Dial.
 func (proxy *Proxy) Create(testURL string, wg *sync.WaitGroup) {
-	socksVersions := [2]int{5, 4}
-	for _, version := range socksVersions {
-		proxy.URL = fmt.Sprintf("socks%d://%s:%d", version, proxy.ip, proxy.port)
-		proxy.dial = socks.Dial(proxy.URL + "?timeout=20s")
-		proxy.transport = &http.Transport{
-			Dial:              proxy.dial,
-			DisableKeepAlives: true,
-		}
-		proxy.client = &http.Client{Transport: proxy.transport, Timeout: 20 * time.Second}
-		_, err := proxy.Get(testURL)
-		if err == nil {
-			break
+	defer wg.Done()
+
+	switch proxy.protocol {
+	case "http", "https":
+		proxy.createHTTPTransport()
+		proxy.Get(testURL)
+	case "socks4":
+		proxy.createSOCKSTransport(4)
+		proxy.Get(testURL)
+	case "socks5":
+		proxy.createSOCKSTransport(5)
+		proxy.Get(testURL)
+	default:
+		for _, version := range [2]int{5, 4} {
+			proxy.createSOCKSTransport(version)
+			if _, err := proxy.Get(testURL); err == nil {
+				return
+			}
 		}
 	}
-	wg.Done()
 }
 
 // Get fetchs an URL with the given proxy and returns the body text
 func (proxy *Proxy) Get(url string) ([]byte, error) {
-	proxy.lastRequest = time.Now()
+	proxy.touch()
+	start := time.Now()
 	resp, err := proxy.client.Get(url)
 	if err != nil {
-		proxy.Alive = false
-		fmt.Println(err)
+		proxy.setAlive(false)
+		proxy.recordResult(false, time.Since(start))
+		proxy.logf("HTTP request failed: %s", err)
 		return nil, fmt.Errorf("HTTP request failed: %s", err)
 	}
 	defer resp.Body.Close()
 
 	buf, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		proxy.Alive = false
+		proxy.setAlive(false)
+		proxy.recordResult(false, time.Since(start))
 		return nil, fmt.Errorf("can't read response: %s", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		proxy.Alive = false
+		proxy.setAlive(false)
+		proxy.recordResult(false, time.Since(start))
 		return nil, fmt.Errorf("test URL replied with HTTP code %d", resp.StatusCode)
 	}
-	proxy.Alive = true
+	proxy.setAlive(true)
+	proxy.recordResult(true, time.Since(start))
 	return buf, nil
 }
 
-// GetAvailableProxy returns an available proxy from the pool.
+// checkWithValidator probes the proxy against testURL and reports liveness
+// using a caller-supplied validator over the raw body and response, instead
+// of the default "status 200" check performed by Get.
+func (proxy *Proxy) checkWithValidator(testURL string, validate func([]byte, *http.Response) bool) bool {
+	proxy.touch()
+	start := time.Now()
+	resp, err := proxy.client.Get(testURL)
+	if err != nil {
+		proxy.setAlive(false)
+		proxy.recordResult(false, time.Since(start))
+		return false
+	}
+	defer resp.Body.Close()
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		proxy.setAlive(false)
+		proxy.recordResult(false, time.Since(start))
+		return false
+	}
+
+	ok := validate(buf, resp)
+	proxy.setAlive(ok)
+	proxy.recordResult(ok, time.Since(start))
+	return ok
+}
+
+// GetAvailableProxy returns an available proxy from the pool, chosen by the
+// pool's Selector among the proxies that are alive and past their rate limit.
 func (pool *ProxyPool) GetAvailableProxy() (*Proxy, error) {
+	pool.ensureSelector()
+
+	pool.proxiesMu.RLock()
+	defer pool.proxiesMu.RUnlock()
+
+	var available []*Proxy
 	for i := range pool.proxies {
-		if time.Since(pool.proxies[i].lastRequest).Seconds() > float64(pool.RetestDelay) && !pool.proxies[i].Alive {
+		if time.Since(pool.proxies[i].LastRequest()).Seconds() > float64(pool.RetestDelay) && !pool.proxies[i].IsAlive() {
 			go pool.proxies[i].Get(pool.TestURL)
 		}
-		if time.Since(pool.proxies[i].lastRequest).Seconds() > float64(pool.RateLimit) && pool.proxies[i].Alive {
-			return pool.proxies[i], nil
+		if time.Since(pool.proxies[i].LastRequest()).Seconds() > float64(pool.RateLimit) && pool.proxies[i].IsAlive() {
+			available = append(available, pool.proxies[i])
 		}
 	}
-	return nil, errors.New("no proxies available")
+	if len(available) == 0 {
+		return nil, errors.New("no proxies available")
+	}
+	return pool.selector.Select(available)
+}
+
+// ensureSelector lazily defaults the pool's Selector to RoundRobinSelector.
+// NewProxyPool already sets one, but a ProxyPool built via struct literal
+// (e.g. to pre-set Logger before the first load) would otherwise leave
+// GetAvailableProxy with a nil selector.
+func (pool *ProxyPool) ensureSelector() {
+	pool.selectorMu.Lock()
+	defer pool.selectorMu.Unlock()
+	if pool.selector == nil {
+		pool.selector = &RoundRobinSelector{}
+	}
 }
 
 // NewProxyPool constructs a new ProxyPool instance
 func NewProxyPool(testURL string, rateLimit int, retestDelay int) *ProxyPool {
-	pool := &ProxyPool{TestURL: testURL, RateLimit: rateLimit, RetestDelay: retestDelay}
+	pool := &ProxyPool{TestURL: testURL, RateLimit: rateLimit, RetestDelay: retestDelay, selector: &RoundRobinSelector{}}
 	return pool
 }
 
-// LoadProxies loads the pool with SOCKS4/5 proxies from a text file
+// LoadProxies loads the pool with proxies from a text file. Each line may be
+// a bare "host:port" or "host:port:user:pass" (SOCKS4/5 auto-detected), or
+// carry an explicit scheme such as "http://host:port" or
+// "socks5://host:port:user:pass". It's a thin wrapper around FileSource.
 func (pool *ProxyPool) LoadProxies(path string) error {
-	file, err := os.Open(path)
+	lines, err := (FileSource{Path: path}).Lines()
 	if err != nil {
-		log.Fatal(err)
-	}
-	defer file.Close()
-
-	var proxies []*Proxy
-	scanner := bufio.NewScanner(file)
-	var wg sync.WaitGroup
-	for scanner.Scan() {
-		line := strings.Split(scanner.Text(), ":")
-		ip := line[0]
-		port, _ := strconv.Atoi(line[1])
-		proxy := Proxy{ip: ip, port: port}
-		proxies = append(proxies, &proxy)
-
-		wg.Add(1)
-		go proxy.Create(pool.TestURL, &wg)
-	}
-	wg.Wait()
-
-	for _, p := range proxies {
-		if p.Alive {
-			pool.proxies = append(pool.proxies, p)
-		}
-	}
-
-	if err := scanner.Err(); err != nil {
 		return err
 	}
-
-	pool.UpdateCounts()
+	pool.ingest(lines)
 	return nil
 }
 
-// Get waits until a proxy from the pool is available and then fetchs the given URL.
-func (pool *ProxyPool) Get(url string) []byte {
-	for {
-		proxy, err := pool.GetAvailableProxy()
-		if err == nil {
-			r, reqErr := proxy.Get(url)
-			if reqErr == nil {
-				return r
-			}
-		}
-		time.Sleep(time.Second)
-	}
-}
-
-// UpdateCounts updates the pool counters
+// UpdateCounts updates the pool counters. Safe to call concurrently, e.g.
+// from the health checker goroutine.
 func (pool *ProxyPool) UpdateCounts() {
+	pool.proxiesMu.RLock()
+	defer pool.proxiesMu.RUnlock()
+
 	var alive, available int
 	for i := range pool.proxies {
-		if pool.proxies[i].Alive {
+		if pool.proxies[i].IsAlive() {
 			alive++
-			if time.Since(pool.proxies[i].lastRequest).Seconds() > float64(pool.RateLimit) {
+			if time.Since(pool.proxies[i].LastRequest()).Seconds() > float64(pool.RateLimit) {
 				available++
 			}
 		}
 	}
+	pool.countsMu.Lock()
 	pool.AliveCount = alive
 	pool.AvailableCount = available
+	pool.countsMu.Unlock()
 }
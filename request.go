@@ -0,0 +1,116 @@
+package proxypool
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RequestOptions customizes a single Do or GetCtx call.
+type RequestOptions struct {
+	// MaxRetries is how many additional proxies to try after the first
+	// failure before giving up. Defaults to 3 if zero.
+	MaxRetries int
+	// StickyProxy, if set, pins the request to a single proxy instead of
+	// drawing one from the pool - useful for login-bearing scrapes that need
+	// a consistent source IP. A failure through it is returned without retry.
+	StickyProxy *Proxy
+	// Headers are added to the outgoing request before it's sent.
+	Headers http.Header
+	// PerProxyTimeout overrides the default client timeout for this request.
+	PerProxyTimeout time.Duration
+}
+
+// Do sends req through a pool-selected proxy, honoring ctx cancellation and
+// deadline instead of retrying forever. On failure it retries with a
+// different proxy (unless opts.StickyProxy is set) up to opts.MaxRetries
+// times before returning the last error.
+func (pool *ProxyPool) Do(ctx context.Context, req *http.Request, opts *RequestOptions) (*http.Response, error) {
+	if opts == nil {
+		opts = &RequestOptions{}
+	}
+	maxRetries := opts.MaxRetries
+	if maxRetries <= 0 {
+		maxRetries = 3
+	}
+	for name, values := range opts.Headers {
+		for _, value := range values {
+			req.Header.Add(name, value)
+		}
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		proxy := opts.StickyProxy
+		if proxy == nil {
+			var err error
+			proxy, err = pool.GetAvailableProxy()
+			if err != nil {
+				lastErr = err
+				if !sleepOrDone(ctx, time.Second) {
+					return nil, ctx.Err()
+				}
+				continue
+			}
+		}
+
+		timeout := opts.PerProxyTimeout
+		if timeout <= 0 {
+			timeout = defaultProxyTimeout
+		}
+		client := &http.Client{Transport: proxy.transport, Timeout: timeout}
+
+		proxy.touch()
+		start := time.Now()
+		resp, err := client.Do(req.Clone(ctx))
+		if err != nil {
+			proxy.setAlive(false)
+			proxy.recordResult(false, time.Since(start))
+			proxy.logf("HTTP request failed: %s", err)
+			lastErr = err
+			if opts.StickyProxy != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		proxy.setAlive(true)
+		proxy.recordResult(true, time.Since(start))
+		return resp, nil
+	}
+	return nil, fmt.Errorf("proxypool: all retries failed: %s", lastErr)
+}
+
+// GetCtx fetches url through a pool-selected proxy and returns the body,
+// honoring ctx cancellation and deadline.
+func (pool *ProxyPool) GetCtx(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := pool.Do(ctx, req, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	return ioutil.ReadAll(resp.Body)
+}
+
+// sleepOrDone waits for d, returning false early if ctx is canceled first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
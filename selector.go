@@ -0,0 +1,94 @@
+package proxypool
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Selector picks which proxy to use for the next request out of the given
+// set of currently-available proxies.
+type Selector interface {
+	Select(proxies []*Proxy) (*Proxy, error)
+}
+
+// RoundRobinSelector cycles through the available proxies in order.
+type RoundRobinSelector struct {
+	mu    sync.Mutex
+	index int
+}
+
+// Select returns the next proxy in rotation.
+func (s *RoundRobinSelector) Select(proxies []*Proxy) (*Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, errors.New("no proxies available")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	proxy := proxies[s.index%len(proxies)]
+	s.index++
+	return proxy, nil
+}
+
+// RandomSelector picks a uniformly random proxy on every call.
+type RandomSelector struct{}
+
+// Select returns a random proxy.
+func (RandomSelector) Select(proxies []*Proxy) (*Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, errors.New("no proxies available")
+	}
+	return proxies[rand.Intn(len(proxies))], nil
+}
+
+// LRUSelector picks the proxy that went longest without handling a request.
+type LRUSelector struct{}
+
+// Select returns the least recently used proxy.
+func (LRUSelector) Select(proxies []*Proxy) (*Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, errors.New("no proxies available")
+	}
+	oldest := proxies[0]
+	for _, proxy := range proxies[1:] {
+		if proxy.LastRequest().Before(oldest.LastRequest()) {
+			oldest = proxy
+		}
+	}
+	return oldest, nil
+}
+
+// WeightedLatencySelector favors proxies with lower measured latency,
+// picking randomly with weights inversely proportional to each proxy's
+// EWMA response time. Proxies with no latency sample yet are treated as
+// having a one-second latency so they still get a chance to be tried.
+type WeightedLatencySelector struct{}
+
+// Select returns a latency-weighted random proxy.
+func (WeightedLatencySelector) Select(proxies []*Proxy) (*Proxy, error) {
+	if len(proxies) == 0 {
+		return nil, errors.New("no proxies available")
+	}
+
+	weights := make([]float64, len(proxies))
+	var total float64
+	for i, proxy := range proxies {
+		latency := proxy.Latency()
+		weight := 1.0
+		if latency > 0 {
+			weight = float64(time.Second) / float64(latency)
+		}
+		weights[i] = weight
+		total += weight
+	}
+
+	target := rand.Float64() * total
+	for i, weight := range weights {
+		target -= weight
+		if target <= 0 {
+			return proxies[i], nil
+		}
+	}
+	return proxies[len(proxies)-1], nil
+}
@@ -0,0 +1,124 @@
+package proxypool
+
+import (
+	"testing"
+	"time"
+)
+
+func newTestProxies(n int) []*Proxy {
+	proxies := make([]*Proxy, n)
+	for i := range proxies {
+		proxies[i] = &Proxy{URL: string(rune('a' + i))}
+	}
+	return proxies
+}
+
+func TestRoundRobinSelectorCycles(t *testing.T) {
+	proxies := newTestProxies(3)
+	var s RoundRobinSelector
+
+	for i := 0; i < len(proxies)*2; i++ {
+		got, err := s.Select(proxies)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		want := proxies[i%len(proxies)]
+		if got != want {
+			t.Fatalf("iteration %d: got %v, want %v", i, got.URL, want.URL)
+		}
+	}
+}
+
+func TestRoundRobinSelectorEmpty(t *testing.T) {
+	var s RoundRobinSelector
+	if _, err := s.Select(nil); err == nil {
+		t.Fatal("expected error for empty proxy list")
+	}
+}
+
+func TestRandomSelectorPicksFromSet(t *testing.T) {
+	proxies := newTestProxies(5)
+	for i := 0; i < 20; i++ {
+		got, err := (RandomSelector{}).Select(proxies)
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		found := false
+		for _, p := range proxies {
+			if p == got {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Fatalf("Select returned a proxy not in the input set: %v", got.URL)
+		}
+	}
+}
+
+func TestRandomSelectorEmpty(t *testing.T) {
+	if _, err := (RandomSelector{}).Select(nil); err == nil {
+		t.Fatal("expected error for empty proxy list")
+	}
+}
+
+func TestLRUSelectorPicksOldest(t *testing.T) {
+	proxies := newTestProxies(3)
+	proxies[1].touch()
+	proxies[2].touch()
+	// proxies[0] keeps the zero-value LastRequest, which is already the oldest.
+
+	got, err := (LRUSelector{}).Select(proxies)
+	if err != nil {
+		t.Fatalf("Select: %v", err)
+	}
+	if got != proxies[0] {
+		t.Fatalf("got %v, want the proxy with the oldest LastRequest", got.URL)
+	}
+}
+
+func TestLRUSelectorEmpty(t *testing.T) {
+	if _, err := (LRUSelector{}).Select(nil); err == nil {
+		t.Fatal("expected error for empty proxy list")
+	}
+}
+
+func TestWeightedLatencySelectorFavorsLowerLatency(t *testing.T) {
+	fast := &Proxy{URL: "fast"}
+	fast.recordResult(true, 10*time.Millisecond)
+	slow := &Proxy{URL: "slow"}
+	slow.recordResult(true, time.Second)
+
+	counts := map[*Proxy]int{}
+	for i := 0; i < 200; i++ {
+		got, err := (WeightedLatencySelector{}).Select([]*Proxy{fast, slow})
+		if err != nil {
+			t.Fatalf("Select: %v", err)
+		}
+		counts[got]++
+	}
+	if counts[fast] <= counts[slow] {
+		t.Fatalf("expected the lower-latency proxy to be picked more often, got fast=%d slow=%d", counts[fast], counts[slow])
+	}
+}
+
+func TestWeightedLatencySelectorEmpty(t *testing.T) {
+	if _, err := (WeightedLatencySelector{}).Select(nil); err == nil {
+		t.Fatal("expected error for empty proxy list")
+	}
+}
+
+func TestGetAvailableProxyDefaultsNilSelector(t *testing.T) {
+	pool := &ProxyPool{TestURL: "http://example.com"}
+	proxy := &Proxy{URL: "http://1.2.3.4:8080"}
+	proxy.setAlive(true)
+	pool.proxies = []*Proxy{proxy}
+
+	got, err := pool.GetAvailableProxy()
+	if err != nil {
+		t.Fatalf("GetAvailableProxy: %v", err)
+	}
+	if got != proxy {
+		t.Fatalf("got %v, want %v", got.URL, proxy.URL)
+	}
+}
@@ -0,0 +1,159 @@
+package proxypool
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+)
+
+// retryBackoff is how long the server waits before trying a different proxy
+// after a failed attempt.
+const retryBackoff = 500 * time.Millisecond
+
+// Server exposes a ProxyPool as a local HTTP/HTTPS forward proxy. Downstream
+// clients can set HTTP_PROXY/HTTPS_PROXY to the server's address and have
+// each request routed through a pool-selected backend proxy.
+type Server struct {
+	Pool       *ProxyPool
+	Addr       string
+	MaxRetries int
+}
+
+// NewServer constructs a Server that forwards requests through pool.
+// maxRetries is how many times a failed upstream request is retried with a
+// different proxy before the server replies with a 502 to the client.
+func NewServer(pool *ProxyPool, addr string, maxRetries int) *Server {
+	return &Server{Pool: pool, Addr: addr, MaxRetries: maxRetries}
+}
+
+// ListenAndServe starts the forward proxy and blocks until it returns an error.
+func (s *Server) ListenAndServe() error {
+	return http.ListenAndServe(s.Addr, s)
+}
+
+// ServeHTTP implements http.Handler, dispatching CONNECT tunnels for HTTPS
+// and plain request forwarding for HTTP.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodConnect {
+		s.handleConnect(w, r)
+		return
+	}
+	s.handleHTTP(w, r)
+}
+
+// handleHTTP forwards a plain HTTP request through a pool-selected proxy,
+// retrying with a different proxy up to MaxRetries times on failure.
+func (s *Server) handleHTTP(w http.ResponseWriter, r *http.Request) {
+	var lastErr error
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		if attempt > 0 && !sleepOrDone(r.Context(), retryBackoff) {
+			break
+		}
+
+		proxy, err := s.Pool.GetAvailableProxy()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		outReq := r.Clone(r.Context())
+		outReq.RequestURI = ""
+
+		proxy.touch()
+		start := time.Now()
+		resp, err := proxy.transport.RoundTrip(outReq)
+		if err != nil {
+			proxy.setAlive(false)
+			proxy.recordResult(false, time.Since(start))
+			proxy.logf("HTTP request failed: %s", err)
+			lastErr = err
+			continue
+		}
+		proxy.setAlive(true)
+		proxy.recordResult(true, time.Since(start))
+		defer resp.Body.Close()
+
+		copyHeader(w.Header(), resp.Header)
+		w.WriteHeader(resp.StatusCode)
+		io.Copy(w, resp.Body)
+		return
+	}
+	http.Error(w, fmt.Sprintf("proxypool: all proxies failed: %s", lastErr), http.StatusBadGateway)
+}
+
+// handleConnect establishes a CONNECT tunnel to r.Host through a
+// pool-selected proxy's dial func and splices bytes bidirectionally
+// between the client and remote connections.
+func (s *Server) handleConnect(w http.ResponseWriter, r *http.Request) {
+	var lastErr error
+	for attempt := 0; attempt <= s.MaxRetries; attempt++ {
+		if attempt > 0 && !sleepOrDone(r.Context(), retryBackoff) {
+			break
+		}
+
+		proxy, err := s.Pool.GetAvailableProxy()
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		proxy.touch()
+		start := time.Now()
+		remote, err := proxy.dial("tcp", r.Host)
+		if err != nil {
+			proxy.setAlive(false)
+			proxy.recordResult(false, time.Since(start))
+			proxy.logf("CONNECT dial failed: %s", err)
+			lastErr = err
+			continue
+		}
+		proxy.setAlive(true)
+		proxy.recordResult(true, time.Since(start))
+
+		hijacker, ok := w.(http.Hijacker)
+		if !ok {
+			remote.Close()
+			http.Error(w, "proxypool: connection doesn't support hijacking", http.StatusInternalServerError)
+			return
+		}
+		client, _, err := hijacker.Hijack()
+		if err != nil {
+			remote.Close()
+			http.Error(w, fmt.Sprintf("proxypool: hijack failed: %s", err), http.StatusInternalServerError)
+			return
+		}
+
+		client.Write([]byte("HTTP/1.1 200 Connection Established\r\n\r\n"))
+		splice(client, remote)
+		return
+	}
+	http.Error(w, fmt.Sprintf("proxypool: all proxies failed: %s", lastErr), http.StatusBadGateway)
+}
+
+// splice copies bytes bidirectionally between two connections until either
+// side closes, then closes both.
+func splice(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// copyHeader copies all header values from src into dst.
+func copyHeader(dst, src http.Header) {
+	for k, vv := range src {
+		for _, v := range vv {
+			dst.Add(k, v)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package proxypool
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Source supplies candidate proxy lines, in the same format LoadProxies
+// accepts, to a pool.
+type Source interface {
+	Lines() ([]string, error)
+}
+
+// FileSource reads proxy lines from a local file.
+type FileSource struct {
+	Path string
+}
+
+// Lines returns every line in the file at Path, blank lines included.
+func (s FileSource) Lines() ([]string, error) {
+	file, err := os.Open(s.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// HTTPSource fetches a remote proxy list, one proxy per line, over HTTP.
+type HTTPSource struct {
+	URL    string
+	Client *http.Client
+}
+
+// Lines fetches the URL and splits its body into lines.
+func (s HTTPSource) Lines() ([]string, error) {
+	client := s.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	resp, err := client.Get(s.URL)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("HTTP source replied with HTTP code %d", resp.StatusCode)
+	}
+
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Split(string(buf), "\n"), nil
+}
+
+// StdinSource reads proxy lines from standard input.
+type StdinSource struct{}
+
+// Lines reads lines from stdin until EOF.
+func (StdinSource) Lines() ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
+
+// FuncSource adapts an arbitrary callback into a Source.
+type FuncSource func() []string
+
+// Lines calls the wrapped callback.
+func (fn FuncSource) Lines() ([]string, error) {
+	return fn(), nil
+}
+
+// AddSource registers a Source with the pool. The lines it returns are
+// de-duplicated against the existing pool and validated concurrently,
+// exactly like LoadProxies. If interval is non-zero, the source is
+// re-polled on that schedule until the pool is stopped; a zero interval
+// fetches once.
+func (pool *ProxyPool) AddSource(source Source, interval time.Duration) {
+	pool.fetchSource(source)
+	if interval <= 0 {
+		return
+	}
+
+	ctx := pool.sourceContext()
+	pool.srcWG.Add(1)
+	go func() {
+		defer pool.srcWG.Done()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				pool.fetchSource(source)
+			}
+		}
+	}()
+}
+
+func (pool *ProxyPool) sourceContext() context.Context {
+	pool.srcOnce.Do(func() {
+		pool.srcCtx, pool.srcCancel = context.WithCancel(context.Background())
+	})
+	return pool.srcCtx
+}
+
+func (pool *ProxyPool) fetchSource(source Source) {
+	lines, err := source.Lines()
+	if err != nil {
+		return
+	}
+	pool.ingest(lines)
+}
+
+// ingest parses lines into proxies, drops ones already known to the pool,
+// validates the rest concurrently and adds the ones that come up alive.
+func (pool *ProxyPool) ingest(lines []string) {
+	seen := make(map[string]bool)
+	var candidates []*Proxy
+	for _, line := range lines {
+		text := strings.TrimSpace(line)
+		if text == "" {
+			continue
+		}
+		proxy, err := parseProxyLine(text)
+		if err != nil {
+			continue
+		}
+
+		key := fmt.Sprintf("%s:%d", proxy.ip, proxy.port)
+		if seen[key] || pool.hasProxy(proxy) {
+			continue
+		}
+		seen[key] = true
+		candidates = append(candidates, proxy)
+	}
+	if len(candidates) == 0 {
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, proxy := range candidates {
+		wg.Add(1)
+		go proxy.Create(pool.TestURL, &wg)
+	}
+	wg.Wait()
+
+	pool.proxiesMu.Lock()
+	for _, proxy := range candidates {
+		if proxy.IsAlive() && !pool.hasProxyLocked(proxy) {
+			proxy.logger = pool.Logger
+			pool.proxies = append(pool.proxies, proxy)
+		}
+	}
+	pool.proxiesMu.Unlock()
+
+	pool.UpdateCounts()
+}
+
+// hasProxy reports whether a proxy at the same address is already in the pool.
+func (pool *ProxyPool) hasProxy(candidate *Proxy) bool {
+	pool.proxiesMu.RLock()
+	defer pool.proxiesMu.RUnlock()
+	return pool.hasProxyLocked(candidate)
+}
+
+// hasProxyLocked is hasProxy's body for callers that already hold proxiesMu
+// as a writer. ingest uses it to re-check for a duplicate address right
+// before appending a validated candidate, closing the window where two
+// sources racing on the same not-yet-present address could both validate
+// and append it.
+func (pool *ProxyPool) hasProxyLocked(candidate *Proxy) bool {
+	for _, proxy := range pool.proxies {
+		if proxy.ip == candidate.ip && proxy.port == candidate.port {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package proxypool
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// proxyState is the JSON-serializable snapshot of a single proxy, used by
+// SaveState/LoadState so a long-running process can restart without
+// re-probing every endpoint from scratch. LastSuccess holds the proxy's last
+// request attempt time, whether that attempt succeeded or failed.
+type proxyState struct {
+	URL         string    `json:"url"`
+	Alive       bool      `json:"alive"`
+	LastSuccess time.Time `json:"last_success"`
+	LatencyMS   int64     `json:"latency_ms"`
+}
+
+// SaveState serializes the pool's proxies to path as JSON.
+func (pool *ProxyPool) SaveState(path string) error {
+	pool.proxiesMu.RLock()
+	states := make([]proxyState, 0, len(pool.proxies))
+	for _, proxy := range pool.proxies {
+		states = append(states, proxyState{
+			URL:         proxy.URL,
+			Alive:       proxy.IsAlive(),
+			LastSuccess: proxy.LastRequest(),
+			LatencyMS:   proxy.Latency().Milliseconds(),
+		})
+	}
+	pool.proxiesMu.RUnlock()
+
+	buf, err := json.MarshalIndent(states, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, buf, 0644)
+}
+
+// LoadState restores proxies previously saved with SaveState, rebuilding
+// their dial/transport/client from the saved URL without re-validating them
+// against TestURL.
+func (pool *ProxyPool) LoadState(path string) error {
+	buf, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+
+	var states []proxyState
+	if err := json.Unmarshal(buf, &states); err != nil {
+		return err
+	}
+
+	pool.proxiesMu.Lock()
+	for _, state := range states {
+		proxy, err := proxyFromState(state)
+		if err != nil {
+			continue
+		}
+		proxy.logger = pool.Logger
+		pool.proxies = append(pool.proxies, proxy)
+	}
+	pool.proxiesMu.Unlock()
+
+	pool.UpdateCounts()
+	return nil
+}
+
+// proxyFromState rebuilds a live Proxy (with its dial/transport/client) from
+// a saved snapshot.
+func proxyFromState(state proxyState) (*Proxy, error) {
+	// SOCKS URLs carry credentials as "host:port:user:pass", which url.Parse
+	// rejects; HTTP/HTTPS URLs carry them as userinfo, which parseProxyLine
+	// rejects.
+	proxy, err := parseProxyLine(state.URL)
+	if err != nil {
+		parsed, err := url.Parse(state.URL)
+		if err != nil {
+			return nil, err
+		}
+
+		port, err := strconv.Atoi(parsed.Port())
+		if err != nil {
+			return nil, err
+		}
+
+		proxy = &Proxy{protocol: parsed.Scheme, ip: parsed.Hostname(), port: port}
+		if parsed.User != nil {
+			proxy.user = parsed.User.Username()
+			proxy.pass, _ = parsed.User.Password()
+		}
+	}
+
+	switch proxy.protocol {
+	case "http", "https":
+		proxy.createHTTPTransport()
+	case "socks4":
+		proxy.createSOCKSTransport(4)
+	default:
+		proxy.createSOCKSTransport(5)
+	}
+
+	proxy.setAlive(state.Alive)
+	proxy.setLastRequest(state.LastSuccess)
+	proxy.latencyEWMA = int64(state.LatencyMS) * int64(time.Millisecond)
+	return proxy, nil
+}
@@ -0,0 +1,128 @@
+package proxypool
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"h12.io/socks"
+)
+
+// defaultProxyTimeout is the request timeout applied to a proxy's client
+// when no more specific timeout has been configured.
+const defaultProxyTimeout = 20 * time.Second
+
+// parseProxyLine parses a single line from a proxy list file into a Proxy.
+// Accepted forms are "host:port", "host:port:user:pass", or any of those
+// prefixed with a "scheme://" (http, https, socks4, socks5).
+func parseProxyLine(line string) (*Proxy, error) {
+	scheme := ""
+	rest := line
+	if idx := strings.Index(line, "://"); idx != -1 {
+		scheme = line[:idx]
+		rest = line[idx+len("://"):]
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 2 && len(parts) != 4 {
+		return nil, fmt.Errorf("invalid proxy line: %s", line)
+	}
+
+	port, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return nil, fmt.Errorf("invalid port in proxy line: %s", line)
+	}
+
+	proxy := &Proxy{protocol: scheme, ip: parts[0], port: port}
+	if len(parts) == 4 {
+		proxy.user = parts[2]
+		proxy.pass = parts[3]
+	}
+	return proxy, nil
+}
+
+// createSOCKSTransport builds the dial, transport and client for a SOCKS4/5
+// proxy. Any credentials are appended to the dial address as
+// "host:port:user:pass"; they are not put in the dial query string.
+func (proxy *Proxy) createSOCKSTransport(version int) {
+	addr := fmt.Sprintf("%s:%d", proxy.ip, proxy.port)
+	if proxy.user != "" {
+		addr = fmt.Sprintf("%s:%d:%s:%s", proxy.ip, proxy.port, proxy.user, proxy.pass)
+	}
+	proxy.protocol = fmt.Sprintf("socks%d", version)
+	proxy.URL = fmt.Sprintf("%s://%s", proxy.protocol, addr)
+	proxy.dial = socks.Dial(proxy.URL + "?timeout=20s")
+	proxy.transport = &http.Transport{
+		Dial:              proxy.dial,
+		DisableKeepAlives: true,
+	}
+	proxy.client = &http.Client{Transport: proxy.transport, Timeout: 20 * time.Second}
+}
+
+// createHTTPTransport builds the dial, transport and client for an HTTP or
+// HTTPS proxy, passing any credentials via the proxy URL's userinfo.
+func (proxy *Proxy) createHTTPTransport() {
+	proxyURL := &url.URL{
+		Scheme: proxy.protocol,
+		Host:   fmt.Sprintf("%s:%d", proxy.ip, proxy.port),
+	}
+	if proxy.user != "" {
+		proxyURL.User = url.UserPassword(proxy.user, proxy.pass)
+	}
+	proxy.URL = proxyURL.String()
+	proxy.dial = httpConnectDial(proxyURL)
+	proxy.transport = &http.Transport{
+		Proxy:             http.ProxyURL(proxyURL),
+		DisableKeepAlives: true,
+	}
+	proxy.client = &http.Client{Transport: proxy.transport, Timeout: 20 * time.Second}
+}
+
+// httpConnectDial returns a dial func that tunnels to addr through an
+// HTTP/HTTPS proxy via the CONNECT method, for use where a net.Conn is
+// needed directly (e.g. splicing a CONNECT tunnel in Server).
+func httpConnectDial(proxyURL *url.URL) func(network, addr string) (net.Conn, error) {
+	return func(network, addr string) (net.Conn, error) {
+		conn, err := net.DialTimeout(network, proxyURL.Host, 20*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		if proxyURL.Scheme == "https" {
+			conn = tls.Client(conn, &tls.Config{ServerName: proxyURL.Hostname()})
+		}
+
+		connectReq := &http.Request{
+			Method: http.MethodConnect,
+			URL:    &url.URL{Opaque: addr},
+			Host:   addr,
+			Header: make(http.Header),
+		}
+		if proxyURL.User != nil {
+			password, _ := proxyURL.User.Password()
+			creds := base64.StdEncoding.EncodeToString([]byte(proxyURL.User.Username() + ":" + password))
+			connectReq.Header.Set("Proxy-Authorization", "Basic "+creds)
+		}
+		if err := connectReq.Write(conn); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		resp, err := http.ReadResponse(bufio.NewReader(conn), connectReq)
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			conn.Close()
+			return nil, fmt.Errorf("proxy CONNECT failed: %s", resp.Status)
+		}
+		return conn, nil
+	}
+}